@@ -0,0 +1,221 @@
+// Copyright 2016, Timothy Bogdala <tdb@animal-machine.com>
+// See the LICENSE file for more details.
+
+package voxfile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// maxVoxelCoordinate is the largest coordinate value a voxel may have along
+// any axis, as dictated by the VOX file format (each model is limited to a
+// 256x256x256 grid).
+const maxVoxelCoordinate = 255
+
+// EncodeFile writes the VoxFile passed in out to the filename specified
+// using the VOX file format.
+func EncodeFile(fn string, v *VoxFile) error {
+	file, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if err := Encode(writer, v); err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
+
+// Encode writes the VoxFile passed in out to the writer using the VOX
+// file format: the magic string and version number followed by a MAIN
+// chunk containing a PACK chunk (when there's more than one model), a
+// SIZE/XYZI pair per model, and, if the palette isn't the default one,
+// an RGBA chunk.
+func Encode(w io.Writer, v *VoxFile) error {
+	if v == nil {
+		return fmt.Errorf("Cannot encode a nil VoxFile.")
+	}
+
+	models := encodeModels(v)
+	if err := validateVoxFile(v, models); err != nil {
+		return err
+	}
+
+	if _, err := w.Write([]byte("VOX ")); err != nil {
+		return fmt.Errorf("Failed to write the VOX magic string. %v", err)
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(CurrentVersion)); err != nil {
+		return fmt.Errorf("Failed to write the version number. %v", err)
+	}
+
+	var packBytes []byte
+	if len(models) > 1 {
+		packBytes = make([]byte, 4)
+		binary.LittleEndian.PutUint32(packBytes, uint32(len(models)))
+	}
+
+	var rgbaBytes []byte
+	if !paletteIsDefault(v.Palette) {
+		rgbaBytes = encodeRGBAChunk(v)
+	}
+
+	childrenSize := uint32(0)
+	if packBytes != nil {
+		childrenSize += uint32(len(packBytes) + 12)
+	}
+	for _, m := range models {
+		childrenSize += uint32(len(encodeSizeChunk(m))+12) + uint32(len(encodeXYZIChunk(m))+12)
+	}
+	if rgbaBytes != nil {
+		childrenSize += uint32(len(rgbaBytes) + 12)
+	}
+
+	if err := writeChunkHeader(w, "MAIN", 0, childrenSize); err != nil {
+		return err
+	}
+	if packBytes != nil {
+		if err := writeChunk(w, "PACK", packBytes); err != nil {
+			return err
+		}
+	}
+	for _, m := range models {
+		if err := writeChunk(w, "SIZE", encodeSizeChunk(m)); err != nil {
+			return err
+		}
+		if err := writeChunk(w, "XYZI", encodeXYZIChunk(m)); err != nil {
+			return err
+		}
+	}
+	if rgbaBytes != nil {
+		if err := writeChunk(w, "RGBA", rgbaBytes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeModels returns the models to encode. When v.Models is populated it
+// is used directly; otherwise the top-level Size*/Voxels fields are used
+// as a single model, for callers that build a VoxFile by hand without
+// touching Models.
+func encodeModels(v *VoxFile) []*Model {
+	if len(v.Models) > 0 {
+		return v.Models
+	}
+	return []*Model{{SizeX: v.SizeX, SizeY: v.SizeY, SizeZ: v.SizeZ, Voxels: v.Voxels}}
+}
+
+// validateVoxFile makes sure the contents of v are within the bounds
+// imposed by the VOX file format before any bytes are written out.
+func validateVoxFile(v *VoxFile, models []*Model) error {
+	for mi, m := range models {
+		if m.SizeX > maxVoxelCoordinate+1 || m.SizeY > maxVoxelCoordinate+1 || m.SizeZ > maxVoxelCoordinate+1 {
+			return fmt.Errorf("Model #%d size (%d, %d, %d) exceeds the maximum of 256 along an axis.", mi, m.SizeX, m.SizeY, m.SizeZ)
+		}
+
+		for i, vx := range m.Voxels {
+			if vx.X > maxVoxelCoordinate || vx.Y > maxVoxelCoordinate || vx.Z > maxVoxelCoordinate {
+				return fmt.Errorf("Model #%d voxel #%d (%d, %d, %d) has a coordinate outside of the 0-255 range.", mi, i, vx.X, vx.Y, vx.Z)
+			}
+		}
+	}
+
+	if v.Palette != nil && len(v.Palette) != 256 {
+		return fmt.Errorf("Palette must contain exactly 256 colors, but has %d.", len(v.Palette))
+	}
+
+	return nil
+}
+
+// writeChunkHeader writes out the 4-byte chunk ID followed by the chunk's
+// content size and children size.
+func writeChunkHeader(w io.Writer, id string, size, childrenSize uint32) error {
+	if _, err := w.Write([]byte(id)); err != nil {
+		return fmt.Errorf("Failed to write the %s chunk ID. %v", id, err)
+	}
+	if err := binary.Write(w, binary.LittleEndian, size); err != nil {
+		return fmt.Errorf("Failed to write the %s chunk size. %v", id, err)
+	}
+	if err := binary.Write(w, binary.LittleEndian, childrenSize); err != nil {
+		return fmt.Errorf("Failed to write the %s chunk's children size. %v", id, err)
+	}
+	return nil
+}
+
+// writeChunk writes out a leaf chunk (one with no children) given its
+// already-encoded content.
+func writeChunk(w io.Writer, id string, content []byte) error {
+	if err := writeChunkHeader(w, id, uint32(len(content)), 0); err != nil {
+		return err
+	}
+	if _, err := w.Write(content); err != nil {
+		return fmt.Errorf("Failed to write the %s chunk contents. %v", id, err)
+	}
+	return nil
+}
+
+// encodeSizeChunk builds the content bytes for a SIZE chunk.
+func encodeSizeChunk(m *Model) []byte {
+	b := make([]byte, 12)
+	binary.LittleEndian.PutUint32(b[0:4], m.SizeX)
+	binary.LittleEndian.PutUint32(b[4:8], m.SizeY)
+	binary.LittleEndian.PutUint32(b[8:12], m.SizeZ)
+	return b
+}
+
+// encodeXYZIChunk builds the content bytes for an XYZI chunk: the voxel
+// count followed by each voxel's X, Y, Z and palette index.
+func encodeXYZIChunk(m *Model) []byte {
+	b := make([]byte, 4+len(m.Voxels)*4)
+	binary.LittleEndian.PutUint32(b[0:4], uint32(len(m.Voxels)))
+	for i, vx := range m.Voxels {
+		offset := 4 + i*4
+		b[offset+0] = vx.X
+		b[offset+1] = vx.Y
+		b[offset+2] = vx.Z
+		b[offset+3] = vx.Index
+	}
+	return b
+}
+
+// encodeRGBAChunk builds the content bytes for an RGBA chunk from the
+// VoxFile's custom palette.
+func encodeRGBAChunk(v *VoxFile) []byte {
+	b := make([]byte, 256*4)
+	for i := 0; i < 256; i++ {
+		c := v.Palette[i]
+		offset := i * 4
+		b[offset+0] = c.R
+		b[offset+1] = c.G
+		b[offset+2] = c.B
+		b[offset+3] = c.A
+	}
+	return b
+}
+
+// paletteIsDefault returns true if p is nil or matches the default palette
+// exactly, in which case an RGBA chunk doesn't need to be written out.
+func paletteIsDefault(p []*Color) bool {
+	if p == nil {
+		return true
+	}
+	if len(p) != 256 {
+		return false
+	}
+
+	def := instancePalette(defaultPalette)
+	for i, c := range p {
+		if c == nil || *c != *def[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+// Copyright 2016, Timothy Bogdala <tdb@animal-machine.com>
+// See the LICENSE file for more details.
+
+package voxfile
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// TestEncodeDecodeRoundTrip makes sure that decoding a file, encoding it
+// back out, and decoding it again produces an identical VoxFile.
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	original, err := DecodeFile(voxfileCharacter)
+	if err != nil || original == nil {
+		t.Fatalf("Failed to load the VOX file %s.\n%v", voxfileCharacter, err)
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, original); err != nil {
+		t.Fatalf("Failed to encode the VOX file. %v", err)
+	}
+
+	roundTripped, err := Decode(bufio.NewReader(&buf))
+	if err != nil || roundTripped == nil {
+		t.Fatalf("Failed to decode the re-encoded VOX file. %v", err)
+	}
+
+	if roundTripped.SizeX != original.SizeX || roundTripped.SizeY != original.SizeY || roundTripped.SizeZ != original.SizeZ {
+		t.Errorf("Round-tripped size (%d, %d, %d) doesn't match the original (%d, %d, %d).",
+			roundTripped.SizeX, roundTripped.SizeY, roundTripped.SizeZ, original.SizeX, original.SizeY, original.SizeZ)
+	}
+
+	if !reflect.DeepEqual(roundTripped.Voxels, original.Voxels) {
+		t.Errorf("Round-tripped voxels don't match the original.")
+	}
+
+	if !reflect.DeepEqual(roundTripped.Palette, original.Palette) {
+		t.Errorf("Round-tripped palette doesn't match the original.")
+	}
+}
+
+// TestEncodeDecodeCustomPalette makes sure a VoxFile with a non-default
+// palette round-trips through an RGBA chunk instead of silently falling
+// back to the default palette.
+func TestEncodeDecodeCustomPalette(t *testing.T) {
+	original := newCustomPaletteVoxFile()
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, original); err != nil {
+		t.Fatalf("Failed to encode the VOX file with a custom palette. %v", err)
+	}
+
+	roundTripped, err := Decode(bufio.NewReader(&buf))
+	if err != nil || roundTripped == nil {
+		t.Fatalf("Failed to decode the re-encoded VOX file. %v", err)
+	}
+
+	if !reflect.DeepEqual(roundTripped.Palette, original.Palette) {
+		t.Errorf("Round-tripped custom palette doesn't match the original.")
+	}
+}
+
+// TestEncodeDecodeMultiModel makes sure a VoxFile with more than one model
+// round-trips through a PACK chunk correctly.
+func TestEncodeDecodeMultiModel(t *testing.T) {
+	original := &VoxFile{
+		Version: CurrentVersion,
+		Models: []*Model{
+			{SizeX: 2, SizeY: 2, SizeZ: 2, Voxels: []*Voxel{{X: 0, Y: 0, Z: 0, Index: 1}}},
+			{SizeX: 3, SizeY: 3, SizeZ: 3, Voxels: []*Voxel{{X: 1, Y: 1, Z: 1, Index: 2}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, original); err != nil {
+		t.Fatalf("Failed to encode the multi-model VOX file. %v", err)
+	}
+
+	roundTripped, err := Decode(bufio.NewReader(&buf))
+	if err != nil || roundTripped == nil {
+		t.Fatalf("Failed to decode the re-encoded multi-model VOX file. %v", err)
+	}
+
+	if len(roundTripped.Models) != 2 {
+		t.Fatalf("Expected 2 models, got %d.", len(roundTripped.Models))
+	}
+
+	for i, m := range original.Models {
+		if !reflect.DeepEqual(roundTripped.Models[i].Voxels, m.Voxels) {
+			t.Errorf("Model #%d voxels don't match the original.", i)
+		}
+	}
+
+	if roundTripped.SizeX != original.Models[0].SizeX || !reflect.DeepEqual(roundTripped.Voxels, original.Models[0].Voxels) {
+		t.Errorf("Top-level alias doesn't match the first model.")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2016, Timothy Bogdala <tdb@animal-machine.com>
+// See the LICENSE file for more details.
+
+package voxfile
+
+import "fmt"
+
+// Grid is a dense, random-access voxel buffer: a single flat, X-major
+// []uint8 where index 0 means "empty" and 1-255 are palette indices. It's
+// meant for meshing, editing, or CSG-style work where walking a sparse
+// slice of *Voxel is awkward, and its flat backing array can be handed
+// directly to things like GPU uploads or a greedy mesher.
+type Grid struct {
+	SizeX uint32
+	SizeY uint32
+	SizeZ uint32
+
+	data []uint8
+}
+
+// NewGrid allocates an empty Grid of the given dimensions.
+func NewGrid(x, y, z uint32) *Grid {
+	return &Grid{
+		SizeX: x,
+		SizeY: y,
+		SizeZ: z,
+		data:  make([]uint8, x*y*z),
+	}
+}
+
+// index computes the flat, X-major offset for (x, y, z) and panics if any
+// coordinate is out of bounds.
+func (g *Grid) index(x, y, z uint32) int {
+	if x >= g.SizeX || y >= g.SizeY || z >= g.SizeZ {
+		panic(fmt.Sprintf("voxfile: Grid coordinate (%d, %d, %d) is out of bounds for size (%d, %d, %d)", x, y, z, g.SizeX, g.SizeY, g.SizeZ))
+	}
+	return int(x*g.SizeY*g.SizeZ + y*g.SizeZ + z)
+}
+
+// Get returns the palette index at (x, y, z), or 0 if the voxel is empty.
+func (g *Grid) Get(x, y, z uint32) uint8 {
+	return g.data[g.index(x, y, z)]
+}
+
+// Set stores the palette index idx at (x, y, z). Use idx 0 to clear the
+// voxel.
+func (g *Grid) Set(x, y, z uint32, idx uint8) {
+	g.data[g.index(x, y, z)] = idx
+}
+
+// ForEach calls fn once for every non-empty voxel in the grid, in X-major
+// order.
+func (g *Grid) ForEach(fn func(x, y, z uint32, idx uint8)) {
+	for x := uint32(0); x < g.SizeX; x++ {
+		for y := uint32(0); y < g.SizeY; y++ {
+			for z := uint32(0); z < g.SizeZ; z++ {
+				idx := g.data[g.index(x, y, z)]
+				if idx != 0 {
+					fn(x, y, z, idx)
+				}
+			}
+		}
+	}
+}
+
+// ToGrid converts the VoxFile's first model into a dense Grid.
+func (v *VoxFile) ToGrid() *Grid {
+	g := NewGrid(v.SizeX, v.SizeY, v.SizeZ)
+	for _, vx := range v.Voxels {
+		g.Set(uint32(vx.X), uint32(vx.Y), uint32(vx.Z), vx.Index)
+	}
+	return g
+}
+
+// GridToVoxFile converts a dense Grid back into a VoxFile, sparsifying the
+// non-empty voxels and attaching the palette passed in.
+func GridToVoxFile(g *Grid, palette []*Color) *VoxFile {
+	v := &VoxFile{
+		Version: CurrentVersion,
+		SizeX:   g.SizeX,
+		SizeY:   g.SizeY,
+		SizeZ:   g.SizeZ,
+		Palette: palette,
+	}
+
+	g.ForEach(func(x, y, z uint32, idx uint8) {
+		v.Voxels = append(v.Voxels, &Voxel{X: uint8(x), Y: uint8(y), Z: uint8(z), Index: idx})
+	})
+
+	v.Models = []*Model{{SizeX: v.SizeX, SizeY: v.SizeY, SizeZ: v.SizeZ, Voxels: v.Voxels}}
+
+	return v
+}
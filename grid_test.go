@@ -0,0 +1,75 @@
+// Copyright 2016, Timothy Bogdala <tdb@animal-machine.com>
+// See the LICENSE file for more details.
+
+package voxfile
+
+import (
+	"sort"
+	"testing"
+)
+
+// TestGridGetSet makes sure a Grid's Get/Set round-trip correctly and that
+// unset voxels read back as empty.
+func TestGridGetSet(t *testing.T) {
+	g := NewGrid(4, 4, 4)
+
+	if g.Get(1, 2, 3) != 0 {
+		t.Errorf("Expected a freshly allocated Grid to be empty.")
+	}
+
+	g.Set(1, 2, 3, 42)
+	if got := g.Get(1, 2, 3); got != 42 {
+		t.Errorf("Expected Get(1, 2, 3) to be 42, got %d.", got)
+	}
+}
+
+// TestGridOutOfBoundsPanics makes sure accessing a coordinate outside the
+// Grid's bounds panics instead of silently corrupting data.
+func TestGridOutOfBoundsPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected an out-of-bounds Get to panic.")
+		}
+	}()
+
+	g := NewGrid(2, 2, 2)
+	g.Get(2, 0, 0)
+}
+
+// TestVoxFileGridRoundTrip makes sure a decoded VoxFile survives a
+// ToGrid/GridToVoxFile round trip.
+func TestVoxFileGridRoundTrip(t *testing.T) {
+	original, err := DecodeFile(voxfileCharacter)
+	if err != nil || original == nil {
+		t.Fatalf("Failed to load the VOX file %s.\n%v", voxfileCharacter, err)
+	}
+
+	grid := original.ToGrid()
+	roundTripped := GridToVoxFile(grid, original.Palette)
+
+	if len(roundTripped.Voxels) != len(original.Voxels) {
+		t.Fatalf("Expected %d voxels after the round trip, got %d.", len(original.Voxels), len(roundTripped.Voxels))
+	}
+
+	originalKeys := voxelKeys(original.Voxels)
+	roundTrippedKeys := voxelKeys(roundTripped.Voxels)
+	sort.Strings(originalKeys)
+	sort.Strings(roundTrippedKeys)
+
+	for i := range originalKeys {
+		if originalKeys[i] != roundTrippedKeys[i] {
+			t.Errorf("Round-tripped voxel set doesn't match the original.")
+			break
+		}
+	}
+}
+
+// voxelKeys builds a sortable, comparable key per voxel so two voxel
+// slices can be compared as sets regardless of order.
+func voxelKeys(voxels []*Voxel) []string {
+	keys := make([]string, len(voxels))
+	for i, v := range voxels {
+		keys[i] = string([]byte{v.X, v.Y, v.Z, v.Index})
+	}
+	return keys
+}
@@ -0,0 +1,94 @@
+// Copyright 2016, Timothy Bogdala <tdb@animal-machine.com>
+// See the LICENSE file for more details.
+
+package voxfile
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// Axis selects which axis a slice is taken along in (*VoxFile).Slice.
+const (
+	AxisX = iota
+	AxisY
+	AxisZ
+)
+
+// RGBA implements color.Color, returning the alpha-premultiplied
+// components in the range [0, 0xffff] expected by the image/color
+// package.
+func (c Color) RGBA() (r, g, b, a uint32) {
+	r = uint32(c.R)
+	r |= r << 8
+	g = uint32(c.G)
+	g |= g << 8
+	b = uint32(c.B)
+	b |= b << 8
+	a = uint32(c.A)
+	a |= a << 8
+
+	r = (r * a) / 0xffff
+	g = (g * a) / 0xffff
+	b = (b * a) / 0xffff
+	return
+}
+
+// ColorPalette returns the VoxFile's palette as a color.Palette of 256
+// color.NRGBA entries, suitable for use with image/draw, image/gif, and
+// the standard PNG encoder.
+func (v *VoxFile) ColorPalette() color.Palette {
+	source := v.Palette
+	if source == nil {
+		source = instancePalette(defaultPalette)
+	}
+
+	pal := make(color.Palette, 256)
+	for i, c := range source {
+		if c == nil {
+			pal[i] = color.NRGBA{}
+			continue
+		}
+		pal[i] = color.NRGBA{R: c.R, G: c.G, B: c.B, A: c.A}
+	}
+	return pal
+}
+
+// Slice produces a 2D paletted image of the voxel data at the given index
+// along axis (AxisX, AxisY or AxisZ), useful for previews, thumbnails, or
+// any pipeline built around image.Image.
+func (v *VoxFile) Slice(axis int, index uint32) *image.Paletted {
+	grid := v.ToGrid()
+
+	var width, height uint32
+	switch axis {
+	case AxisX:
+		width, height = grid.SizeY, grid.SizeZ
+	case AxisY:
+		width, height = grid.SizeX, grid.SizeZ
+	case AxisZ:
+		width, height = grid.SizeX, grid.SizeY
+	default:
+		panic(fmt.Sprintf("voxfile: unknown axis %d", axis))
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, int(width), int(height)), v.ColorPalette())
+
+	for u := uint32(0); u < width; u++ {
+		for w := uint32(0); w < height; w++ {
+			var idx uint8
+			switch axis {
+			case AxisX:
+				idx = grid.Get(index, u, w)
+			case AxisY:
+				idx = grid.Get(u, index, w)
+			case AxisZ:
+				idx = grid.Get(u, w, index)
+			}
+			img.SetColorIndex(int(u), int(w), idx)
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,119 @@
+// Copyright 2016, Timothy Bogdala <tdb@animal-machine.com>
+// See the LICENSE file for more details.
+
+package voxfile
+
+import (
+	"bufio"
+	"bytes"
+	"image/png"
+	"os"
+	"testing"
+)
+
+// TestColorRGBA makes sure Color's color.Color implementation
+// alpha-premultiplies correctly.
+func TestColorRGBA(t *testing.T) {
+	c := Color{R: 0xff, G: 0x80, B: 0x00, A: 0xff}
+	r, g, b, a := c.RGBA()
+	if r>>8 != 0xff || g>>8 != 0x80 || b>>8 != 0x00 || a>>8 != 0xff {
+		t.Errorf("Expected (0xff, 0x80, 0x00, 0xff) in the high byte, got (%x, %x, %x, %x).", r>>8, g>>8, b>>8, a>>8)
+	}
+}
+
+// TestColorPaletteCustom makes sure ColorPalette reflects a file's own
+// RGBA chunk instead of the default palette once it's round-tripped
+// through Encode/Decode.
+func TestColorPaletteCustom(t *testing.T) {
+	original := newCustomPaletteVoxFile()
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, original); err != nil {
+		t.Fatalf("Failed to encode the VOX file with a custom palette. %v", err)
+	}
+
+	decoded, err := Decode(bufio.NewReader(&buf))
+	if err != nil || decoded == nil {
+		t.Fatalf("Failed to decode the re-encoded VOX file. %v", err)
+	}
+
+	pal := decoded.ColorPalette()
+	want := original.Palette[10]
+	r, g, b, _ := pal[10].RGBA()
+	if uint8(r>>8) != want.R || uint8(g>>8) != want.G || uint8(b>>8) != want.B {
+		t.Errorf("Expected palette entry 10 to be (%d, %d, %d), got (%d, %d, %d).",
+			want.R, want.G, want.B, uint8(r>>8), uint8(g>>8), uint8(b>>8))
+	}
+}
+
+// TestSlice makes sure a Z-axis slice of a decoded VoxFile matches the
+// source voxel data, and that it can be PNG-encoded without error.
+func TestSlice(t *testing.T) {
+	voxFile, err := DecodeFile(voxfileCharacter)
+	if err != nil || voxFile == nil {
+		t.Fatalf("Failed to load the VOX file %s.\n%v", voxfileCharacter, err)
+	}
+
+	const z = 0
+	img := voxFile.Slice(AxisZ, z)
+
+	if img.Bounds().Dx() != int(voxFile.SizeX) || img.Bounds().Dy() != int(voxFile.SizeY) {
+		t.Fatalf("Expected a %dx%d image, got %dx%d.", voxFile.SizeX, voxFile.SizeY, img.Bounds().Dx(), img.Bounds().Dy())
+	}
+
+	for _, vx := range voxFile.Voxels {
+		if vx.Z != z {
+			continue
+		}
+		if got := img.ColorIndexAt(int(vx.X), int(vx.Y)); got != vx.Index {
+			t.Errorf("Voxel (%d, %d, %d) expected palette index %d in the slice, got %d.", vx.X, vx.Y, vx.Z, vx.Index, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to PNG-encode the slice. %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Errorf("Expected the PNG encoding to produce bytes.")
+	}
+}
+
+// TestSliceGolden PNG-encodes a slice of a small, hand-built VoxFile with
+// a custom palette and diffs it byte-for-byte against testdata/slice_golden.png,
+// in the style of image/png/reader_test.go. Unlike TestSlice, this catches
+// a colorization or axis-ordering regression that still produces
+// non-empty PNG bytes.
+func TestSliceGolden(t *testing.T) {
+	v := &VoxFile{
+		Version: CurrentVersion,
+		Models: []*Model{
+			{SizeX: 2, SizeY: 2, SizeZ: 1, Voxels: []*Voxel{
+				{X: 0, Y: 0, Z: 0, Index: 1},
+				{X: 1, Y: 1, Z: 0, Index: 2},
+			}},
+		},
+		Palette: make([]*Color, 256),
+	}
+	for i := range v.Palette {
+		v.Palette[i] = &Color{R: uint8(i), G: uint8(255 - i), B: 128, A: 255}
+	}
+	v.SizeX, v.SizeY, v.SizeZ = v.Models[0].SizeX, v.Models[0].SizeY, v.Models[0].SizeZ
+	v.Voxels = v.Models[0].Voxels
+
+	img := v.Slice(AxisZ, 0)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to PNG-encode the slice. %v", err)
+	}
+
+	golden, err := os.ReadFile("testdata/slice_golden.png")
+	if err != nil {
+		t.Fatalf("Failed to read the golden PNG file. %v", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Errorf("Slice PNG doesn't match testdata/slice_golden.png.")
+	}
+}
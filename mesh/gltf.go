@@ -0,0 +1,185 @@
+// Copyright 2016, Timothy Bogdala <tdb@animal-machine.com>
+// See the LICENSE file for more details.
+
+package mesh
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+)
+
+// glTF 2.0 constants used by the accessors/bufferViews this package emits.
+// See https://github.com/KhronosGroup/glTF/tree/main/specification/2.0
+const (
+	gltfComponentTypeFloat       = 5126
+	gltfComponentTypeUnsignedInt = 5125
+	gltfTargetArrayBuffer        = 34962
+	gltfTargetElementArrayBuffer = 34963
+)
+
+type gltfAsset struct {
+	Version string `json:"version"`
+}
+
+type gltfBuffer struct {
+	ByteLength int    `json:"byteLength"`
+	URI        string `json:"uri"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target,omitempty"`
+}
+
+type gltfAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Max           []float32 `json:"max,omitempty"`
+	Min           []float32 `json:"min,omitempty"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    int            `json:"indices"`
+}
+
+type gltfMesh struct {
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfNode struct {
+	Mesh int `json:"mesh"`
+}
+
+type gltfScene struct {
+	Nodes []int `json:"nodes"`
+}
+
+type gltfDocument struct {
+	Asset       gltfAsset        `json:"asset"`
+	Scene       int              `json:"scene"`
+	Scenes      []gltfScene      `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes"`
+	Meshes      []gltfMesh       `json:"meshes"`
+	Buffers     []gltfBuffer     `json:"buffers"`
+	BufferViews []gltfBufferView `json:"bufferViews"`
+	Accessors   []gltfAccessor   `json:"accessors"`
+}
+
+// WriteGLTF writes m out as a self-contained glTF 2.0 asset: one buffer,
+// embedded as a base64 data URI, holding the position, normal, color and
+// index data, and the accessors/bufferViews/mesh/node/scene needed to
+// describe it.
+func WriteGLTF(w io.Writer, m *Mesh) error {
+	var bin bytes.Buffer
+
+	positionsOffset := bin.Len()
+	min, max := boundsOf(m)
+	for _, v := range m.Vertices {
+		writeFloat32s(&bin, v.Position[:])
+	}
+	positionsLength := bin.Len() - positionsOffset
+
+	normalsOffset := bin.Len()
+	for _, v := range m.Vertices {
+		writeFloat32s(&bin, v.Normal[:])
+	}
+	normalsLength := bin.Len() - normalsOffset
+
+	colorsOffset := bin.Len()
+	for _, v := range m.Vertices {
+		writeFloat32s(&bin, v.Color[:])
+	}
+	colorsLength := bin.Len() - colorsOffset
+
+	indicesOffset := bin.Len()
+	for _, idx := range m.Indices {
+		if err := binary.Write(&bin, binary.LittleEndian, idx); err != nil {
+			return err
+		}
+	}
+	indicesLength := bin.Len() - indicesOffset
+
+	doc := gltfDocument{
+		Asset: gltfAsset{Version: "2.0"},
+		Scene: 0,
+		Scenes: []gltfScene{
+			{Nodes: []int{0}},
+		},
+		Nodes: []gltfNode{
+			{Mesh: 0},
+		},
+		Meshes: []gltfMesh{
+			{
+				Primitives: []gltfPrimitive{
+					{
+						Attributes: map[string]int{
+							"POSITION": 0,
+							"NORMAL":   1,
+							"COLOR_0":  2,
+						},
+						Indices: 3,
+					},
+				},
+			},
+		},
+		Buffers: []gltfBuffer{
+			{
+				ByteLength: bin.Len(),
+				URI:        "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(bin.Bytes()),
+			},
+		},
+		BufferViews: []gltfBufferView{
+			{Buffer: 0, ByteOffset: positionsOffset, ByteLength: positionsLength, Target: gltfTargetArrayBuffer},
+			{Buffer: 0, ByteOffset: normalsOffset, ByteLength: normalsLength, Target: gltfTargetArrayBuffer},
+			{Buffer: 0, ByteOffset: colorsOffset, ByteLength: colorsLength, Target: gltfTargetArrayBuffer},
+			{Buffer: 0, ByteOffset: indicesOffset, ByteLength: indicesLength, Target: gltfTargetElementArrayBuffer},
+		},
+		Accessors: []gltfAccessor{
+			{BufferView: 0, ComponentType: gltfComponentTypeFloat, Count: len(m.Vertices), Type: "VEC3", Min: min[:], Max: max[:]},
+			{BufferView: 1, ComponentType: gltfComponentTypeFloat, Count: len(m.Vertices), Type: "VEC3"},
+			{BufferView: 2, ComponentType: gltfComponentTypeFloat, Count: len(m.Vertices), Type: "VEC4"},
+			{BufferView: 3, ComponentType: gltfComponentTypeUnsignedInt, Count: len(m.Indices), Type: "SCALAR"},
+		},
+	}
+
+	enc := json.NewEncoder(w)
+	return enc.Encode(doc)
+}
+
+// writeFloat32s writes each value in vals to buf as a little-endian
+// float32.
+func writeFloat32s(buf *bytes.Buffer, vals []float32) {
+	for _, v := range vals {
+		binary.Write(buf, binary.LittleEndian, v)
+	}
+}
+
+// boundsOf computes the min/max position accessor bounds glTF requires
+// for the POSITION attribute.
+func boundsOf(m *Mesh) (min, max [3]float32) {
+	if len(m.Vertices) == 0 {
+		return min, max
+	}
+
+	min = m.Vertices[0].Position
+	max = m.Vertices[0].Position
+	for _, v := range m.Vertices[1:] {
+		for i := 0; i < 3; i++ {
+			if v.Position[i] < min[i] {
+				min[i] = v.Position[i]
+			}
+			if v.Position[i] > max[i] {
+				max[i] = v.Position[i]
+			}
+		}
+	}
+	return min, max
+}
@@ -0,0 +1,192 @@
+// Copyright 2016, Timothy Bogdala <tdb@animal-machine.com>
+// See the LICENSE file for more details.
+
+// Package mesh converts decoded voxfile models into triangle meshes using
+// greedy face merging, and writes them out as Wavefront OBJ or glTF 2.0.
+package mesh
+
+import "github.com/tbogdala/voxfile"
+
+// Vertex is one corner of a mesh quad.
+type Vertex struct {
+	Position [3]float32
+	Normal   [3]float32
+	Color    [4]float32
+}
+
+// Mesh is a triangle mesh produced by GreedyMesh. Every 4 consecutive
+// Vertices form one quad, and every 6 consecutive Indices form that
+// quad's two triangles.
+type Mesh struct {
+	Vertices []Vertex
+	Indices  []uint32
+}
+
+// direction describes one of the 6 axis-aligned face directions a greedy
+// mesh sweep considers: which axis it sweeps along (0=X, 1=Y, 2=Z), which
+// side of a voxel (+1 or -1) the face sits on, and the resulting face
+// normal.
+type direction struct {
+	axis   int
+	sign   int32
+	normal [3]float32
+}
+
+var directions = [6]direction{
+	{0, 1, [3]float32{1, 0, 0}},
+	{0, -1, [3]float32{-1, 0, 0}},
+	{1, 1, [3]float32{0, 1, 0}},
+	{1, -1, [3]float32{0, -1, 0}},
+	{2, 1, [3]float32{0, 0, 1}},
+	{2, -1, [3]float32{0, 0, -1}},
+}
+
+// GreedyMesh converts a decoded VoxFile into a triangle mesh. For each of
+// the 6 axis-aligned face directions, it sweeps through slices along that
+// axis; for each slice it builds a 2D mask of the palette index of the
+// voxel face visible in that direction (0 where the neighboring voxel
+// hides it), then repeatedly finds the largest axis-aligned rectangle of
+// equal non-zero mask values and emits it as a single quad, until the
+// mask is empty.
+func GreedyMesh(v *voxfile.VoxFile) *Mesh {
+	grid := v.ToGrid()
+	dims := [3]uint32{grid.SizeX, grid.SizeY, grid.SizeZ}
+
+	m := &Mesh{}
+
+	for _, dir := range directions {
+		u := (dir.axis + 1) % 3
+		w := (dir.axis + 2) % 3
+
+		width, height := dims[u], dims[w]
+		if width == 0 || height == 0 || dims[dir.axis] == 0 {
+			continue
+		}
+
+		mask := make([]uint8, width*height)
+
+		for s := uint32(0); s < dims[dir.axis]; s++ {
+			buildMask(mask, grid, dims, dir, u, w, s, width, height)
+			mergeMaskIntoQuads(m, mask, width, height, dir, u, w, s, v.Palette)
+		}
+	}
+
+	return m
+}
+
+// buildMask fills mask[ui*height+wi] with the palette index of the face
+// visible at (s along dir.axis, ui along u, wi along w), or 0 if that
+// voxel is empty or its face is hidden by a solid neighbor.
+func buildMask(mask []uint8, grid *voxfile.Grid, dims [3]uint32, dir direction, u, w int, s, width, height uint32) {
+	for i := range mask {
+		mask[i] = 0
+	}
+
+	for ui := uint32(0); ui < width; ui++ {
+		for wi := uint32(0); wi < height; wi++ {
+			var pos [3]uint32
+			pos[dir.axis] = s
+			pos[u] = ui
+			pos[w] = wi
+
+			idx := grid.Get(pos[0], pos[1], pos[2])
+			if idx == 0 {
+				continue
+			}
+
+			neighbor := int32(s) + dir.sign
+			if neighbor >= 0 && uint32(neighbor) < dims[dir.axis] {
+				npos := pos
+				npos[dir.axis] = uint32(neighbor)
+				if grid.Get(npos[0], npos[1], npos[2]) != 0 {
+					continue // hidden behind a solid neighbor
+				}
+			}
+
+			mask[ui*height+wi] = idx
+		}
+	}
+}
+
+// mergeMaskIntoQuads greedily merges equal, non-zero mask cells into the
+// largest axis-aligned rectangles it can and emits one quad per
+// rectangle, zeroing out the cells it consumes.
+func mergeMaskIntoQuads(m *Mesh, mask []uint8, width, height uint32, dir direction, u, w int, slice uint32, palette []*voxfile.Color) {
+	for wi := uint32(0); wi < height; wi++ {
+		for ui := uint32(0); ui < width; {
+			idx := mask[ui*height+wi]
+			if idx == 0 {
+				ui++
+				continue
+			}
+
+			rectWidth := uint32(1)
+			for ui+rectWidth < width && mask[(ui+rectWidth)*height+wi] == idx {
+				rectWidth++
+			}
+
+			rectHeight := uint32(1)
+		growHeight:
+			for wi+rectHeight < height {
+				for k := uint32(0); k < rectWidth; k++ {
+					if mask[(ui+k)*height+(wi+rectHeight)] != idx {
+						break growHeight
+					}
+				}
+				rectHeight++
+			}
+
+			emitQuad(m, dir, u, w, slice, ui, wi, rectWidth, rectHeight, idx, palette)
+
+			for du := uint32(0); du < rectWidth; du++ {
+				for dh := uint32(0); dh < rectHeight; dh++ {
+					mask[(ui+du)*height+(wi+dh)] = 0
+				}
+			}
+
+			ui += rectWidth
+		}
+	}
+}
+
+// emitQuad appends the 4 vertices and 6 indices (two triangles) for one
+// merged rectangle to m.
+func emitQuad(m *Mesh, dir direction, u, w int, slice, ui, wi, rectWidth, rectHeight uint32, idx uint8, palette []*voxfile.Color) {
+	faceCoord := float32(slice)
+	if dir.sign > 0 {
+		faceCoord = float32(slice + 1)
+	}
+
+	uvCorners := [4][2]float32{
+		{float32(ui), float32(wi)},
+		{float32(ui + rectWidth), float32(wi)},
+		{float32(ui + rectWidth), float32(wi + rectHeight)},
+		{float32(ui), float32(wi + rectHeight)},
+	}
+
+	var corners [4][3]float32
+	for i, c := range uvCorners {
+		var pos [3]float32
+		pos[dir.axis] = faceCoord
+		pos[u] = c[0]
+		pos[w] = c[1]
+		corners[i] = pos
+	}
+
+	if dir.sign < 0 {
+		// reverse winding so the face still points outward
+		corners[1], corners[3] = corners[3], corners[1]
+	}
+
+	col := [4]float32{1, 1, 1, 1}
+	if int(idx) < len(palette) && palette[idx] != nil {
+		c := palette[idx]
+		col = [4]float32{float32(c.R) / 255, float32(c.G) / 255, float32(c.B) / 255, float32(c.A) / 255}
+	}
+
+	base := uint32(len(m.Vertices))
+	for _, p := range corners {
+		m.Vertices = append(m.Vertices, Vertex{Position: p, Normal: dir.normal, Color: col})
+	}
+	m.Indices = append(m.Indices, base, base+1, base+2, base, base+2, base+3)
+}
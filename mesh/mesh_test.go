@@ -0,0 +1,110 @@
+// Copyright 2016, Timothy Bogdala <tdb@animal-machine.com>
+// See the LICENSE file for more details.
+
+package mesh
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tbogdala/voxfile"
+)
+
+const voxfileCharacter = "../testdata/chr_sword.vox"
+
+// TestGreedyMesh makes sure the vertex and index counts produced for
+// chr_sword.vox stay stable and internally consistent: every quad
+// contributes exactly 4 vertices and 6 indices, and no index points past
+// the end of the vertex slice.
+func TestGreedyMesh(t *testing.T) {
+	voxFile, err := voxfile.DecodeFile(voxfileCharacter)
+	if err != nil {
+		t.Fatalf("Failed to load the VOX file %s.\n%v", voxfileCharacter, err)
+	}
+
+	m := GreedyMesh(voxFile)
+	if len(m.Vertices) == 0 {
+		t.Fatalf("Expected GreedyMesh to produce vertices, got none.")
+	}
+	if len(m.Vertices)%4 != 0 {
+		t.Errorf("Expected the vertex count to be a multiple of 4, got %d.", len(m.Vertices))
+	}
+	if len(m.Indices)%6 != 0 {
+		t.Errorf("Expected the index count to be a multiple of 6, got %d.", len(m.Indices))
+	}
+	if len(m.Indices) != len(m.Vertices)/4*6 {
+		t.Errorf("Expected %d indices for %d vertices, got %d.", len(m.Vertices)/4*6, len(m.Vertices), len(m.Indices))
+	}
+
+	for _, idx := range m.Indices {
+		if int(idx) >= len(m.Vertices) {
+			t.Fatalf("Index %d is out of range for %d vertices.", idx, len(m.Vertices))
+		}
+	}
+}
+
+// TestGreedyMeshSingleVoxel hand-computes the expected quad count for the
+// simplest possible case, a single 1x1x1 voxel: all 6 faces are visible
+// (there's no neighbor in any direction to hide one), so the mesh must
+// have exactly 6 quads, 24 vertices and 36 indices. This pins the actual
+// geometry GreedyMesh produces, unlike the internal-consistency checks in
+// TestGreedyMesh above, which would still pass if buildMask's
+// neighbor-hiding condition were flipped.
+func TestGreedyMeshSingleVoxel(t *testing.T) {
+	voxFile := &voxfile.VoxFile{
+		Version: voxfile.CurrentVersion,
+		Models: []*voxfile.Model{
+			{SizeX: 1, SizeY: 1, SizeZ: 1, Voxels: []*voxfile.Voxel{{X: 0, Y: 0, Z: 0, Index: 1}}},
+		},
+	}
+	voxFile.SizeX, voxFile.SizeY, voxFile.SizeZ = 1, 1, 1
+	voxFile.Voxels = voxFile.Models[0].Voxels
+
+	m := GreedyMesh(voxFile)
+
+	const expectedQuads = 6
+	if len(m.Vertices) != expectedQuads*4 {
+		t.Errorf("Expected %d vertices for a single voxel, got %d.", expectedQuads*4, len(m.Vertices))
+	}
+	if len(m.Indices) != expectedQuads*6 {
+		t.Errorf("Expected %d indices for a single voxel, got %d.", expectedQuads*6, len(m.Indices))
+	}
+}
+
+// TestWriteOBJ makes sure a meshed VOX file can be encoded as a Wavefront
+// OBJ file without error.
+func TestWriteOBJ(t *testing.T) {
+	voxFile, err := voxfile.DecodeFile(voxfileCharacter)
+	if err != nil {
+		t.Fatalf("Failed to load the VOX file %s.\n%v", voxfileCharacter, err)
+	}
+
+	m := GreedyMesh(voxFile)
+
+	var buf bytes.Buffer
+	if err := WriteOBJ(&buf, m); err != nil {
+		t.Fatalf("Failed to write the OBJ file. %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Errorf("Expected WriteOBJ to produce output, got none.")
+	}
+}
+
+// TestWriteGLTF makes sure a meshed VOX file can be encoded as a glTF 2.0
+// file without error.
+func TestWriteGLTF(t *testing.T) {
+	voxFile, err := voxfile.DecodeFile(voxfileCharacter)
+	if err != nil {
+		t.Fatalf("Failed to load the VOX file %s.\n%v", voxfileCharacter, err)
+	}
+
+	m := GreedyMesh(voxFile)
+
+	var buf bytes.Buffer
+	if err := WriteGLTF(&buf, m); err != nil {
+		t.Fatalf("Failed to write the glTF file. %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Errorf("Expected WriteGLTF to produce output, got none.")
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2016, Timothy Bogdala <tdb@animal-machine.com>
+// See the LICENSE file for more details.
+
+package mesh
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// WriteOBJ writes m out as a Wavefront OBJ file: one "v" line per vertex
+// (with trailing vertex color, as some OBJ readers accept), one "vn" line
+// per normal, and one triangle "f" line per 3 indices.
+func WriteOBJ(w io.Writer, m *Mesh) error {
+	bw := bufio.NewWriter(w)
+
+	for _, v := range m.Vertices {
+		if _, err := fmt.Fprintf(bw, "v %g %g %g %g %g %g\n",
+			v.Position[0], v.Position[1], v.Position[2],
+			v.Color[0], v.Color[1], v.Color[2]); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range m.Vertices {
+		if _, err := fmt.Fprintf(bw, "vn %g %g %g\n", v.Normal[0], v.Normal[1], v.Normal[2]); err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i+2 < len(m.Indices); i += 3 {
+		a, b, c := m.Indices[i]+1, m.Indices[i+1]+1, m.Indices[i+2]+1
+		if _, err := fmt.Fprintf(bw, "f %d//%d %d//%d %d//%d\n", a, a, b, b, c, c); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,357 @@
+// Copyright 2016, Timothy Bogdala <tdb@animal-machine.com>
+// See the LICENSE file for more details.
+
+package voxfile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SceneNode is implemented by the scene-graph node types (TransformNode,
+// GroupNode and ShapeNode) that make up a version 200 file's nTRN/nGRP/nSHP
+// hierarchy.
+type SceneNode interface {
+	// SceneNodeID returns the node's ID, as referenced by its parent's
+	// child ID list.
+	SceneNodeID() int32
+}
+
+// Frame holds the per-frame transform data stored in an nTRN chunk, with
+// the "_t" and "_r" attributes decoded into Translation and Rotation.
+type Frame struct {
+	// Attributes is the raw frame attribute dict (e.g. "_t", "_r", "_f").
+	Attributes map[string]string
+
+	// Translation is the decoded "_t" attribute, if present.
+	Translation [3]int32
+
+	// Rotation is the decoded "_r" attribute (MagicaVoxel's packed byte
+	// rotation encoding, expanded to a 3x3 matrix), if present.
+	Rotation [3][3]int8
+}
+
+// TransformNode is a scene-graph node (nTRN chunk) describing the
+// transform applied to a single child node.
+type TransformNode struct {
+	NodeID      int32
+	Attributes  map[string]string
+	ChildNodeID int32
+	LayerID     int32
+	Frames      []Frame
+}
+
+// SceneNodeID implements the SceneNode interface.
+func (t *TransformNode) SceneNodeID() int32 { return t.NodeID }
+
+// GroupNode is a scene-graph node (nGRP chunk) holding a list of child
+// node IDs.
+type GroupNode struct {
+	NodeID     int32
+	Attributes map[string]string
+	ChildIDs   []int32
+}
+
+// SceneNodeID implements the SceneNode interface.
+func (g *GroupNode) SceneNodeID() int32 { return g.NodeID }
+
+// ShapeNode is a scene-graph node (nSHP chunk) referencing one or more
+// models by ID.
+type ShapeNode struct {
+	NodeID     int32
+	Attributes map[string]string
+	ModelIDs   []int32
+	ModelAttrs []map[string]string
+}
+
+// SceneNodeID implements the SceneNode interface.
+func (s *ShapeNode) SceneNodeID() int32 { return s.NodeID }
+
+// Layer is a layer definition (LAYR chunk), used to group shape nodes for
+// visibility toggling in MagicaVoxel's editor.
+type Layer struct {
+	ID         uint32
+	Attributes map[string]string
+}
+
+// Material is a material definition (MATL chunk), describing the
+// rendering properties associated with a palette index.
+type Material struct {
+	ID         uint32
+	Attributes map[string]string
+}
+
+// readString reads a length-prefixed string: a uint32 byte count followed
+// by that many bytes.
+func readString(r *bufio.Reader) (string, error) {
+	var length uint32
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return "", fmt.Errorf("Failed to read a string's length. %v", err)
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", fmt.Errorf("Failed to read a string's %d bytes. %v", length, err)
+	}
+
+	return string(buf), nil
+}
+
+// readDict reads a DICT structure: a uint32 entry count followed by that
+// many (key, value) string pairs.
+func readDict(r *bufio.Reader) (map[string]string, error) {
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("Failed to read a DICT's entry count. %v", err)
+	}
+
+	dict := make(map[string]string, count)
+	for i := uint32(0); i < count; i++ {
+		key, err := readString(r)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read a DICT key #%d. %v", i, err)
+		}
+		value, err := readString(r)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read a DICT value #%d. %v", i, err)
+		}
+		dict[key] = value
+	}
+
+	return dict, nil
+}
+
+// readInt32 reads a single little-endian signed 32-bit integer.
+func readInt32(r *bufio.Reader) (int32, error) {
+	var v int32
+	err := binary.Read(r, binary.LittleEndian, &v)
+	return v, err
+}
+
+// readTransformNode reads the body of an nTRN chunk.
+func readTransformNode(r *bufio.Reader) (*TransformNode, error) {
+	nodeID, err := readInt32(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the nTRN node ID. %v", err)
+	}
+	attrs, err := readDict(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the nTRN node attributes. %v", err)
+	}
+	childID, err := readInt32(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the nTRN child node ID. %v", err)
+	}
+	if _, err := readInt32(r); err != nil { // reserved node ID, always -1
+		return nil, fmt.Errorf("Failed to read the nTRN reserved ID. %v", err)
+	}
+	layerID, err := readInt32(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the nTRN layer ID. %v", err)
+	}
+	frameCount, err := readInt32(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the nTRN frame count. %v", err)
+	}
+
+	frames := make([]Frame, frameCount)
+	for i := int32(0); i < frameCount; i++ {
+		frameAttrs, err := readDict(r)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read the nTRN frame #%d attributes. %v", i, err)
+		}
+		frames[i] = Frame{
+			Attributes:  frameAttrs,
+			Translation: decodeTranslation(frameAttrs["_t"]),
+			Rotation:    decodeRotation(frameAttrs["_r"]),
+		}
+	}
+
+	return &TransformNode{
+		NodeID:      nodeID,
+		Attributes:  attrs,
+		ChildNodeID: childID,
+		LayerID:     layerID,
+		Frames:      frames,
+	}, nil
+}
+
+// readGroupNode reads the body of an nGRP chunk.
+func readGroupNode(r *bufio.Reader) (*GroupNode, error) {
+	nodeID, err := readInt32(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the nGRP node ID. %v", err)
+	}
+	attrs, err := readDict(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the nGRP node attributes. %v", err)
+	}
+	childCount, err := readInt32(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the nGRP child count. %v", err)
+	}
+
+	childIDs := make([]int32, childCount)
+	for i := int32(0); i < childCount; i++ {
+		childIDs[i], err = readInt32(r)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read the nGRP child #%d. %v", i, err)
+		}
+	}
+
+	return &GroupNode{NodeID: nodeID, Attributes: attrs, ChildIDs: childIDs}, nil
+}
+
+// readShapeNode reads the body of an nSHP chunk.
+func readShapeNode(r *bufio.Reader) (*ShapeNode, error) {
+	nodeID, err := readInt32(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the nSHP node ID. %v", err)
+	}
+	attrs, err := readDict(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the nSHP node attributes. %v", err)
+	}
+	modelCount, err := readInt32(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the nSHP model count. %v", err)
+	}
+
+	modelIDs := make([]int32, modelCount)
+	modelAttrs := make([]map[string]string, modelCount)
+	for i := int32(0); i < modelCount; i++ {
+		modelIDs[i], err = readInt32(r)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read the nSHP model #%d ID. %v", i, err)
+		}
+		modelAttrs[i], err = readDict(r)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read the nSHP model #%d attributes. %v", i, err)
+		}
+	}
+
+	return &ShapeNode{NodeID: nodeID, Attributes: attrs, ModelIDs: modelIDs, ModelAttrs: modelAttrs}, nil
+}
+
+// readLayer reads the body of a LAYR chunk.
+func readLayer(r *bufio.Reader) (*Layer, error) {
+	id, err := readInt32(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the LAYR layer ID. %v", err)
+	}
+	attrs, err := readDict(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the LAYR layer attributes. %v", err)
+	}
+	if _, err := readInt32(r); err != nil { // reserved ID, always -1
+		return nil, fmt.Errorf("Failed to read the LAYR reserved ID. %v", err)
+	}
+
+	return &Layer{ID: uint32(id), Attributes: attrs}, nil
+}
+
+// readMaterial reads the body of a MATL chunk.
+func readMaterial(r *bufio.Reader) (*Material, error) {
+	id, err := readInt32(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the MATL material ID. %v", err)
+	}
+	attrs, err := readDict(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the MATL material attributes. %v", err)
+	}
+
+	return &Material{ID: uint32(id), Attributes: attrs}, nil
+}
+
+// readNote reads the body of a NOTE chunk: a count followed by that many
+// color name strings.
+func readNote(r *bufio.Reader) ([]string, error) {
+	count, err := readInt32(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the NOTE color name count. %v", err)
+	}
+
+	notes := make([]string, count)
+	for i := int32(0); i < count; i++ {
+		notes[i], err = readString(r)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read the NOTE color name #%d. %v", i, err)
+		}
+	}
+
+	return notes, nil
+}
+
+// readIndexMap reads the body of an IMAP chunk: 256 palette index bytes.
+func readIndexMap(r *bufio.Reader) ([256]uint8, error) {
+	var indexMap [256]uint8
+	if _, err := io.ReadFull(r, indexMap[:]); err != nil {
+		return indexMap, fmt.Errorf("Failed to read the IMAP palette index bytes. %v", err)
+	}
+	return indexMap, nil
+}
+
+// decodeTranslation parses a "_t" frame attribute ("x y z") into a
+// [3]int32. An empty or malformed string decodes to the zero translation.
+func decodeTranslation(s string) [3]int32 {
+	var t [3]int32
+	fields := strings.Fields(s)
+	for i := 0; i < 3 && i < len(fields); i++ {
+		v, err := strconv.Atoi(fields[i])
+		if err != nil {
+			continue
+		}
+		t[i] = int32(v)
+	}
+	return t
+}
+
+// decodeRotation parses a "_r" frame attribute (MagicaVoxel's packed
+// rotation byte, as a decimal string) into a 3x3 rotation matrix. Each row
+// of the matrix has exactly one non-zero entry, +1 or -1, whose column is
+// picked out by 2 bits per row and whose sign is picked out by 1 bit per
+// row; the third row's column is whichever one the first two didn't use.
+func decodeRotation(s string) [3][3]int8 {
+	var m [3][3]int8
+	if s == "" {
+		return m
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return m
+	}
+	b := byte(n)
+
+	col := [3]int{int(b & 0x3), int((b >> 2) & 0x3), 0}
+	used := [3]bool{}
+	used[col[0]] = true
+	used[col[1]] = true
+	for i := 0; i < 3; i++ {
+		if !used[i] {
+			col[2] = i
+		}
+	}
+
+	sign := [3]int8{1, 1, 1}
+	if (b>>4)&1 == 1 {
+		sign[0] = -1
+	}
+	if (b>>5)&1 == 1 {
+		sign[1] = -1
+	}
+	if (b>>6)&1 == 1 {
+		sign[2] = -1
+	}
+
+	for row := 0; row < 3; row++ {
+		m[row][col[row]] = sign[row]
+	}
+
+	return m
+}
@@ -0,0 +1,59 @@
+// Copyright 2016, Timothy Bogdala <tdb@animal-machine.com>
+// See the LICENSE file for more details.
+
+package voxfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// TestReadDict makes sure the DICT reader correctly parses a count
+// followed by that many length-prefixed key/value string pairs.
+func TestReadDict(t *testing.T) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(2))
+	writeDictString(&buf, "_name")
+	writeDictString(&buf, "body")
+	writeDictString(&buf, "_hidden")
+	writeDictString(&buf, "0")
+
+	dict, err := readDict(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatalf("Failed to read the DICT. %v", err)
+	}
+
+	if dict["_name"] != "body" {
+		t.Errorf("Expected _name to be \"body\", got %q.", dict["_name"])
+	}
+	if dict["_hidden"] != "0" {
+		t.Errorf("Expected _hidden to be \"0\", got %q.", dict["_hidden"])
+	}
+}
+
+// writeDictString writes a length-prefixed string the way a DICT chunk
+// expects it.
+func writeDictString(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.LittleEndian, uint32(len(s)))
+	buf.WriteString(s)
+}
+
+// TestDecodeRotation checks a couple of MagicaVoxel's packed rotation
+// bytes against their documented matrices.
+func TestDecodeRotation(t *testing.T) {
+	// identity: row0 -> col0 (+1), row1 -> col1 (+1), row2 -> col2 (+1)
+	identity := decodeRotation("4")
+	want := [3][3]int8{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	if identity != want {
+		t.Errorf("Expected identity matrix %v, got %v.", want, identity)
+	}
+
+	// row0 -> col1 (+1), row1 -> col0 (+1), row2 -> col2 (-1)
+	swapped := decodeRotation("65")
+	want = [3][3]int8{{0, 1, 0}, {1, 0, 0}, {0, 0, -1}}
+	if swapped != want {
+		t.Errorf("Expected swapped matrix %v, got %v.", want, swapped)
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright 2016, Timothy Bogdala <tdb@animal-machine.com>
+// See the LICENSE file for more details.
+
+package voxfile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// ChunkHandler receives callbacks as DecodeStream walks a VOX file,
+// without ever materializing every model, node or material into memory
+// at once. This mirrors the SAX-vs-DOM split used by other RIFF-based
+// readers: Decode is the DOM-style API built on top of a ChunkHandler
+// that populates a VoxFile, while DecodeStream is the low-level SAX-style
+// entry point for callers who only want to look at part of a large file.
+type ChunkHandler interface {
+	// OnSize is called once per model, in order, when its SIZE chunk is
+	// read. model is the model's index (0-based).
+	OnSize(model int, x, y, z uint32) error
+
+	// OnVoxel is called once per voxel in a model's XYZI chunk. model
+	// matches the index passed to the preceding OnSize call.
+	OnVoxel(model int, v Voxel) error
+
+	// OnPalette is called when an RGBA chunk is read.
+	OnPalette(p [256]Color) error
+
+	// OnMaterial is called once per material in a MATL chunk.
+	OnMaterial(id uint32, dict map[string]string) error
+
+	// OnNode is called once per scene-graph node (nTRN/nGRP/nSHP chunk).
+	OnNode(node SceneNode) error
+}
+
+// LayerHandler is an optional interface a ChunkHandler can implement to
+// receive LAYR chunks. Callers that don't care about layers can simply
+// not implement it.
+type LayerHandler interface {
+	OnLayer(layer *Layer) error
+}
+
+// NoteHandler is an optional interface a ChunkHandler can implement to
+// receive the color names from a NOTE chunk.
+type NoteHandler interface {
+	OnNotes(notes []string) error
+}
+
+// IndexMapHandler is an optional interface a ChunkHandler can implement to
+// receive an IMAP chunk's palette index re-mapping.
+type IndexMapHandler interface {
+	OnIndexMap(m [256]uint8) error
+}
+
+// VersionHandler is an optional interface a ChunkHandler can implement to
+// receive the file's version number once it's been read and validated.
+type VersionHandler interface {
+	OnVersion(version uint32) error
+}
+
+// DecodeStream walks the VOX file data in r chunk by chunk, calling the
+// matching ChunkHandler method for each piece of data it encounters
+// instead of building a VoxFile. This lets callers process files with
+// many models, layers or scene nodes without allocating a full *Voxel per
+// voxel up front.
+func DecodeStream(r io.Reader, h ChunkHandler) error {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+
+	// Read in and test the 'magic' string
+	var magic [4]byte
+	c, err := br.Read(magic[:])
+	if err != nil {
+		return fmt.Errorf("File doesn't appear to be a VOX file. %v", err)
+	}
+	if c != 4 || magic[0] != 'V' || magic[1] != 'O' || magic[2] != 'X' || magic[3] != ' ' {
+		return fmt.Errorf("File doesn't appear to be a VOX file. (Magic: %v)", magic)
+	}
+
+	// Read in the version number of the file
+	var version uint32
+	if err := binary.Read(br, binary.LittleEndian, &version); err != nil {
+		return fmt.Errorf("Couldn't read the version number from the file. %v", err)
+	}
+	if version != Version150 && version != Version200 {
+		return fmt.Errorf("Version number from the file (%d) is not a supported version (%d or %d).", version, Version150, Version200)
+	}
+	if vh, ok := h.(VersionHandler); ok {
+		if err := vh.OnVersion(version); err != nil {
+			return err
+		}
+	}
+
+	state := &streamState{handler: h, modelIndex: -1}
+	_, err = readChunkStream(br, state)
+	return err
+}
+
+// streamState carries the bookkeeping readChunkStream needs across
+// recursive calls: the handler to call back into, and which model index
+// the most recent SIZE chunk introduced.
+type streamState struct {
+	handler    ChunkHandler
+	modelIndex int
+}
+
+// readChunkStream reads a single chunk from r, dispatching its contents
+// to state.handler, then recurses into its children.
+func readChunkStream(r *bufio.Reader, state *streamState) (bytesRead uint32, err error) {
+	// get the ID
+	var chunkID [4]byte
+	_, err = r.Read(chunkID[:])
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read the chunk ID. %v", err)
+	}
+	chunkIDStr := string(chunkID[:4])
+
+	// get the chunk Size
+	var chunkSize uint32
+	err = binary.Read(r, binary.LittleEndian, &chunkSize)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read the %s chunk size. %v", chunkIDStr, err)
+	}
+
+	// get the children size
+	var chunkChildrenSize uint32
+	err = binary.Read(r, binary.LittleEndian, &chunkChildrenSize)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read the %s chunk's children size. %v", chunkIDStr, err)
+	}
+
+	// read in the chunk if necessary
+	if chunkSize > 0 {
+		if err := dispatchChunk(r, chunkIDStr, chunkSize, state); err != nil {
+			return 0, err
+		}
+	}
+
+	// read children if necessary
+	remainingBytes := chunkChildrenSize
+	for remainingBytes > 0 {
+		childReadSize, err := readChunkStream(r, state)
+		if err != nil {
+			return 0, err
+		}
+		remainingBytes = remainingBytes - childReadSize
+	}
+
+	return chunkSize + 12, nil // +12 bytes for id, size, childSize
+}
+
+// dispatchChunk reads the body of a single non-empty chunk and calls the
+// matching handler method for it.
+func dispatchChunk(r *bufio.Reader, chunkIDStr string, chunkSize uint32, state *streamState) error {
+	switch chunkIDStr {
+	case "PACK":
+		// the payload is just the number of models to follow; the models
+		// themselves arrive as their own SIZE/XYZI chunk pairs, so there's
+		// nothing further to do here but consume the bytes
+		if chunkSize != 4 {
+			return fmt.Errorf("Failed to read the %s chunk. Size should have been 4 but is %d.", chunkIDStr, chunkSize)
+		}
+		if _, err := readInt32(r); err != nil {
+			return fmt.Errorf("Failed to read the %s chunk model count. %v", chunkIDStr, err)
+		}
+
+	case "SIZE":
+		if chunkSize != 12 {
+			return fmt.Errorf("Failed to read the %s chunk. Size should have been 12 but is %d.", chunkIDStr, chunkSize)
+		}
+		var sizeX, sizeY, sizeZ uint32
+		if err := binary.Read(r, binary.LittleEndian, &sizeX); err != nil {
+			return fmt.Errorf("Failed to read the %s chunk X-axis size. %v", chunkIDStr, err)
+		}
+		if err := binary.Read(r, binary.LittleEndian, &sizeY); err != nil {
+			return fmt.Errorf("Failed to read the %s chunk Y-axis size. %v", chunkIDStr, err)
+		}
+		if err := binary.Read(r, binary.LittleEndian, &sizeZ); err != nil {
+			return fmt.Errorf("Failed to read the %s chunk Z-axis size. %v", chunkIDStr, err)
+		}
+
+		// a SIZE chunk always introduces a new model
+		state.modelIndex++
+		if err := state.handler.OnSize(state.modelIndex, sizeX, sizeY, sizeZ); err != nil {
+			return err
+		}
+
+	case "XYZI":
+		var voxelCount uint32
+		if err := binary.Read(r, binary.LittleEndian, &voxelCount); err != nil {
+			return fmt.Errorf("Failed to read the %s chunk voxel count. %v", chunkIDStr, err)
+		}
+
+		for i := uint32(0); i < voxelCount; i++ {
+			var vX, vY, vZ, vI uint8
+			if err := binary.Read(r, binary.LittleEndian, &vX); err != nil {
+				return fmt.Errorf("Failed to read the %s chunk voxel #%d. %v", chunkIDStr, i, err)
+			}
+			if err := binary.Read(r, binary.LittleEndian, &vY); err != nil {
+				return fmt.Errorf("Failed to read the %s chunk voxel #%d. %v", chunkIDStr, i, err)
+			}
+			if err := binary.Read(r, binary.LittleEndian, &vZ); err != nil {
+				return fmt.Errorf("Failed to read the %s chunk voxel #%d. %v", chunkIDStr, i, err)
+			}
+			if err := binary.Read(r, binary.LittleEndian, &vI); err != nil {
+				return fmt.Errorf("Failed to read the %s chunk voxel #%d. %v", chunkIDStr, i, err)
+			}
+
+			if err := state.handler.OnVoxel(state.modelIndex, Voxel{vX, vY, vZ, vI}); err != nil {
+				return err
+			}
+		}
+
+	case "RGBA":
+		// note: the last index isn't used but we'll read it anyway.
+		var palette [256]Color
+		for i := 0; i < 256; i++ {
+			var vR, vG, vB, vA uint8
+			if err := binary.Read(r, binary.LittleEndian, &vR); err != nil {
+				return fmt.Errorf("Failed to read the %s chunk color #%d. %v", chunkIDStr, i, err)
+			}
+			if err := binary.Read(r, binary.LittleEndian, &vG); err != nil {
+				return fmt.Errorf("Failed to read the %s chunk color #%d. %v", chunkIDStr, i, err)
+			}
+			if err := binary.Read(r, binary.LittleEndian, &vB); err != nil {
+				return fmt.Errorf("Failed to read the %s chunk color #%d. %v", chunkIDStr, i, err)
+			}
+			if err := binary.Read(r, binary.LittleEndian, &vA); err != nil {
+				return fmt.Errorf("Failed to read the %s chunk color #%d. %v", chunkIDStr, i, err)
+			}
+			palette[i] = Color{vR, vG, vB, vA}
+		}
+		if err := state.handler.OnPalette(palette); err != nil {
+			return err
+		}
+
+	case "nTRN":
+		node, err := readTransformNode(r)
+		if err != nil {
+			return err
+		}
+		return state.handler.OnNode(node)
+
+	case "nGRP":
+		node, err := readGroupNode(r)
+		if err != nil {
+			return err
+		}
+		return state.handler.OnNode(node)
+
+	case "nSHP":
+		node, err := readShapeNode(r)
+		if err != nil {
+			return err
+		}
+		return state.handler.OnNode(node)
+
+	case "LAYR":
+		layer, err := readLayer(r)
+		if err != nil {
+			return err
+		}
+		if lh, ok := state.handler.(LayerHandler); ok {
+			return lh.OnLayer(layer)
+		}
+
+	case "MATL":
+		material, err := readMaterial(r)
+		if err != nil {
+			return err
+		}
+		return state.handler.OnMaterial(material.ID, material.Attributes)
+
+	case "NOTE":
+		notes, err := readNote(r)
+		if err != nil {
+			return err
+		}
+		if nh, ok := state.handler.(NoteHandler); ok {
+			return nh.OnNotes(notes)
+		}
+
+	case "IMAP":
+		indexMap, err := readIndexMap(r)
+		if err != nil {
+			return err
+		}
+		if ih, ok := state.handler.(IndexMapHandler); ok {
+			return ih.OnIndexMap(indexMap)
+		}
+
+	case "rOBJ":
+		// scene-wide rendering attributes; no handler callback for these yet
+		if _, err := readDict(r); err != nil {
+			return err
+		}
+
+	case "rCAM":
+		// camera ID followed by its attribute DICT; no handler callback for these yet
+		if _, err := readInt32(r); err != nil {
+			return fmt.Errorf("Failed to read the %s camera ID. %v", chunkIDStr, err)
+		}
+		if _, err := readDict(r); err != nil {
+			return err
+		}
+
+	default:
+		// this is a chunk that must have been deprecated in the new file format.
+		// just read it in and ditch the data
+		var chunkID [4]byte
+		totalRead := 0
+		for totalRead < int(chunkSize) {
+			c, err := r.Read(chunkID[:])
+			if err != nil {
+				return fmt.Errorf("Failed to read the %s chunk contents. %v", chunkIDStr, err)
+			}
+			totalRead += c
+		}
+	}
+
+	return nil
+}
+
+// voxFileHandler is the ChunkHandler Decode uses internally to populate a
+// VoxFile from a stream of chunk callbacks.
+type voxFileHandler struct {
+	voxFile *VoxFile
+}
+
+func (h *voxFileHandler) OnSize(model int, x, y, z uint32) error {
+	h.voxFile.Models = append(h.voxFile.Models, &Model{SizeX: x, SizeY: y, SizeZ: z})
+	return nil
+}
+
+func (h *voxFileHandler) OnVoxel(model int, v Voxel) error {
+	if model < 0 || model >= len(h.voxFile.Models) {
+		return fmt.Errorf("Received a voxel for model #%d before its SIZE chunk.", model)
+	}
+	m := h.voxFile.Models[model]
+	if uint32(v.X) >= m.SizeX || uint32(v.Y) >= m.SizeY || uint32(v.Z) >= m.SizeZ {
+		return fmt.Errorf("Voxel (%d, %d, %d) for model #%d is out of bounds for its SIZE of (%d, %d, %d).",
+			v.X, v.Y, v.Z, model, m.SizeX, m.SizeY, m.SizeZ)
+	}
+	m.Voxels = append(m.Voxels, &v)
+	return nil
+}
+
+func (h *voxFileHandler) OnPalette(p [256]Color) error {
+	palette := make([]*Color, 256)
+	for i := range p {
+		c := p[i]
+		palette[i] = &c
+	}
+	h.voxFile.Palette = palette
+	return nil
+}
+
+func (h *voxFileHandler) OnMaterial(id uint32, dict map[string]string) error {
+	if h.voxFile.Materials == nil {
+		h.voxFile.Materials = make(map[uint32]*Material)
+	}
+	h.voxFile.Materials[id] = &Material{ID: id, Attributes: dict}
+	return nil
+}
+
+func (h *voxFileHandler) OnNode(node SceneNode) error {
+	h.voxFile.Scene = append(h.voxFile.Scene, node)
+	return nil
+}
+
+func (h *voxFileHandler) OnLayer(layer *Layer) error {
+	h.voxFile.Layers = append(h.voxFile.Layers, layer)
+	return nil
+}
+
+func (h *voxFileHandler) OnNotes(notes []string) error {
+	h.voxFile.Notes = notes
+	return nil
+}
+
+func (h *voxFileHandler) OnIndexMap(m [256]uint8) error {
+	h.voxFile.IndexMap = m
+	return nil
+}
+
+func (h *voxFileHandler) OnVersion(version uint32) error {
+	h.voxFile.Version = version
+	return nil
+}
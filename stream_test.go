@@ -0,0 +1,87 @@
+// Copyright 2016, Timothy Bogdala <tdb@animal-machine.com>
+// See the LICENSE file for more details.
+
+package voxfile
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"testing"
+)
+
+// countingHandler is a minimal ChunkHandler that only tallies what it's
+// told about, to prove DecodeStream can be used without ever building a
+// VoxFile.
+type countingHandler struct {
+	sizes      int
+	voxels     int
+	gotPalette bool
+}
+
+func (c *countingHandler) OnSize(model int, x, y, z uint32) error {
+	c.sizes++
+	return nil
+}
+
+func (c *countingHandler) OnVoxel(model int, v Voxel) error {
+	c.voxels++
+	return nil
+}
+
+func (c *countingHandler) OnPalette(p [256]Color) error {
+	c.gotPalette = true
+	return nil
+}
+
+func (c *countingHandler) OnMaterial(id uint32, dict map[string]string) error {
+	return nil
+}
+
+func (c *countingHandler) OnNode(node SceneNode) error {
+	return nil
+}
+
+// TestDecodeStream makes sure DecodeStream calls a minimal ChunkHandler
+// with the same model/voxel counts that Decode would have materialized.
+func TestDecodeStream(t *testing.T) {
+	file, err := os.Open(voxfileCharacter)
+	if err != nil {
+		t.Fatalf("Failed to open %s. %v", voxfileCharacter, err)
+	}
+	defer file.Close()
+
+	handler := &countingHandler{}
+	if err := DecodeStream(bufio.NewReader(file), handler); err != nil {
+		t.Fatalf("Failed to stream-decode the VOX file. %v", err)
+	}
+
+	if handler.sizes != 1 {
+		t.Errorf("Expected 1 SIZE chunk, got %d.", handler.sizes)
+	}
+	if handler.voxels != 334 {
+		t.Errorf("Expected 334 voxels, got %d.", handler.voxels)
+	}
+}
+
+// TestDecodeVoxelOutOfBounds makes sure Decode returns an error instead of
+// letting a voxel outside its model's declared SIZE through, since
+// ToGrid/Slice/mesh.GreedyMesh all assume every voxel fits the grid they
+// allocate.
+func TestDecodeVoxelOutOfBounds(t *testing.T) {
+	bad := &VoxFile{
+		Version: CurrentVersion,
+		Models: []*Model{
+			{SizeX: 2, SizeY: 2, SizeZ: 2, Voxels: []*Voxel{{X: 5, Y: 0, Z: 0, Index: 1}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, bad); err != nil {
+		t.Fatalf("Failed to encode the out-of-bounds VOX file. %v", err)
+	}
+
+	if _, err := Decode(bufio.NewReader(&buf)); err == nil {
+		t.Errorf("Expected Decode to reject a voxel outside its model's SIZE.")
+	}
+}
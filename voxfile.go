@@ -13,14 +13,21 @@ https://ephtracy.github.io/index.html?page=mv_vox_format
 
 import (
 	"bufio"
-	"encoding/binary"
-	"fmt"
 	"os"
 )
 
 const (
-	// CurrentVersion specifies the supported version for the file loader.
-	CurrentVersion = 150
+	// Version150 is the file version written by MagicaVoxel releases prior
+	// to the scene-graph rework (no nTRN/nGRP/nSHP/LAYR/MATL/NOTE/IMAP
+	// chunks).
+	Version150 = 150
+
+	// Version200 is the file version written by MagicaVoxel 0.99 and later,
+	// which adds the scene-graph, layer, material and note chunks.
+	Version200 = 200
+
+	// CurrentVersion specifies the version written by Encode/EncodeFile.
+	CurrentVersion = Version200
 )
 
 // Voxel is the data type that represents one voxel in the file. It has Location
@@ -58,9 +65,52 @@ type VoxFile struct {
 	// Voxels is a slice of all loaded voxels from the file
 	Voxels []*Voxel
 
+	// Models holds every model read from the file. When a PACK chunk is
+	// present there can be more than one; otherwise it holds exactly the
+	// one model described by SizeX/SizeY/SizeZ/Voxels. SizeX, SizeY, SizeZ
+	// and Voxels are kept in sync with Models[0] for backward compatibility.
+	Models []*Model
+
 	// Palette is a 256-size palette of colors that is referenced
 	// in the Voxel structs
 	Palette []*Color
+
+	// Scene holds the scene-graph nodes (nTRN/nGRP/nSHP) read from a
+	// version 200 file, in the order they were encountered.
+	Scene []SceneNode
+
+	// Layers holds the layer definitions (LAYR chunks) read from a
+	// version 200 file.
+	Layers []*Layer
+
+	// Materials maps a material ID to its definition (MATL chunks), read
+	// from a version 200 file.
+	Materials map[uint32]*Material
+
+	// IndexMap holds the palette index re-mapping from an IMAP chunk, if
+	// one was present in the file.
+	IndexMap [256]uint8
+
+	// Notes holds the color names read from a NOTE chunk, if one was
+	// present in the file.
+	Notes []string
+}
+
+// Model represents one voxel grid within a VOX file. Files written by
+// MagicaVoxel versions that support the PACK chunk can hold several of
+// these.
+type Model struct {
+	// SizeX is the size of the X-axis of the model's voxel data
+	SizeX uint32
+
+	// SizeY is the size of the Y-axis of the model's voxel data
+	SizeY uint32
+
+	// SizeZ is the size of the Z-axis of the model's voxel data
+	SizeZ uint32
+
+	// Voxels is a slice of all voxels belonging to this model
+	Voxels []*Voxel
 }
 
 // DecodeFile opens the file specified and reads it in as a VOX file.
@@ -82,176 +132,26 @@ func DecodeFile(fn string) (*VoxFile, error) {
 // to create the vox structures.
 func Decode(r *bufio.Reader) (*VoxFile, error) {
 	voxelFile := new(VoxFile)
+	handler := &voxFileHandler{voxFile: voxelFile}
 
-	// Read in and test the 'magic' string
-	var magic [4]byte
-	c, err := r.Read(magic[:])
-	if err != nil {
-		return nil, fmt.Errorf("File doesn't appear to be a VOX file. %v", err)
-	}
-	if c != 4 || magic[0] != 'V' || magic[1] != 'O' || magic[2] != 'X' || magic[3] != ' ' {
-		return nil, fmt.Errorf("File doesn't appear to be a VOX file. (Magic: %v)", magic)
-	}
-
-	// Read in the version number of the file
-	var version uint32
-	err = binary.Read(r, binary.LittleEndian, &version)
-	if err != nil {
-		return nil, fmt.Errorf("Couldn't read the version number from the file. %v", err)
-	}
-	if version != CurrentVersion {
-		return nil, fmt.Errorf("Version number from the file (%d) doesn't match the current version (%d).", version, CurrentVersion)
-	}
-	voxelFile.Version = version
-
-	_, err = readChunk(r, voxelFile)
+	err := DecodeStream(r, handler)
 
 	// if we didn't have a custom palette, make an instance of the default one
-	voxelFile.Palette = instancePalette(defaultPalette)
-
-	return voxelFile, err
-}
-
-// readChunk reads a chunk from the VOX file.
-func readChunk(r *bufio.Reader, voxFile *VoxFile) (bytesRead uint32, err error) {
-	// get the ID
-	var chunkID [4]byte
-	_, err = r.Read(chunkID[:])
-	if err != nil {
-		return 0, fmt.Errorf("Failed to read the chunk ID. %v", err)
+	if voxelFile.Palette == nil {
+		voxelFile.Palette = instancePalette(defaultPalette)
 	}
-	chunkIDStr := string(chunkID[:4])
 
-	// get the chunk Size
-	var chunkSize uint32
-	err = binary.Read(r, binary.LittleEndian, &chunkSize)
-	if err != nil {
-		return 0, fmt.Errorf("Failed to read the %s chunk size. %v", chunkIDStr, err)
+	// keep the top-level Size*/Voxels fields as an alias for the first
+	// model so that old code reading a single-model file keeps working
+	if len(voxelFile.Models) > 0 {
+		first := voxelFile.Models[0]
+		voxelFile.SizeX = first.SizeX
+		voxelFile.SizeY = first.SizeY
+		voxelFile.SizeZ = first.SizeZ
+		voxelFile.Voxels = first.Voxels
 	}
 
-	// get the children size
-	var chunkChildrenSize uint32
-	err = binary.Read(r, binary.LittleEndian, &chunkChildrenSize)
-	if err != nil {
-		return 0, fmt.Errorf("Failed to read the %s chunk's children size. %v", chunkIDStr, err)
-	}
-
-	// read in the chunk if necessary
-	if chunkSize > 0 {
-		// Some chunks are handled separately
-		if chunkIDStr == "SIZE" {
-			// read the dimensions of the chunk
-			if chunkSize != 12 {
-				return 0, fmt.Errorf("Failed to read the %s chunk. Size should have been 12 but is %d.", chunkIDStr, chunkSize)
-			}
-			var sizeX uint32
-			err = binary.Read(r, binary.LittleEndian, &sizeX)
-			if err != nil {
-				return 0, fmt.Errorf("Failed to read the %s chunk X-axis size. %v", chunkIDStr, err)
-			}
-			var sizeY uint32
-			err = binary.Read(r, binary.LittleEndian, &sizeY)
-			if err != nil {
-				return 0, fmt.Errorf("Failed to read the %s chunk Y-axis size. %v", chunkIDStr, err)
-			}
-			var sizeZ uint32
-			err = binary.Read(r, binary.LittleEndian, &sizeZ)
-			if err != nil {
-				return 0, fmt.Errorf("Failed to read the %s chunk Z-axis size. %v", chunkIDStr, err)
-			}
-
-			voxFile.SizeX = sizeX
-			voxFile.SizeY = sizeY
-			voxFile.SizeZ = sizeZ
-		} else if chunkIDStr == "XYZI" {
-			var voxelCount uint32
-			err = binary.Read(r, binary.LittleEndian, &voxelCount)
-			if err != nil {
-				return 0, fmt.Errorf("Failed to read the %s chunk voxel count. %v", chunkIDStr, err)
-			}
-
-			// make the slice of Voxels for the number of voxels in the file
-			voxels := make([]*Voxel, voxelCount)
-			for i := uint32(0); i < voxelCount; i++ {
-				var vX, vY, vZ, vI uint8
-				err = binary.Read(r, binary.LittleEndian, &vX)
-				if err != nil {
-					return 0, fmt.Errorf("Failed to read the %s chunk voxel #%d. %v", chunkIDStr, i, err)
-				}
-				err = binary.Read(r, binary.LittleEndian, &vY)
-				if err != nil {
-					return 0, fmt.Errorf("Failed to read the %s chunk voxel #%d. %v", chunkIDStr, i, err)
-				}
-				err = binary.Read(r, binary.LittleEndian, &vZ)
-				if err != nil {
-					return 0, fmt.Errorf("Failed to read the %s chunk voxel #%d. %v", chunkIDStr, i, err)
-				}
-				err = binary.Read(r, binary.LittleEndian, &vI)
-				if err != nil {
-					return 0, fmt.Errorf("Failed to read the %s chunk voxel #%d. %v", chunkIDStr, i, err)
-				}
-
-				v := &Voxel{vX, vY, vZ, vI}
-				voxels[i] = v
-			}
-
-			voxFile.Voxels = voxels
-		} else if chunkIDStr == "RGBA" {
-			// we have a fancy lad here with a fancy custom palette.
-			// note: the last index isn't used but we'll read it anyway.
-			const paletteSize = 256
-			customPalette := make([]*Color, 256)
-			for i := 0; i < paletteSize; i++ {
-				var vR, vG, vB, vA uint8
-				err = binary.Read(r, binary.LittleEndian, &vR)
-				if err != nil {
-					return 0, fmt.Errorf("Failed to read the %s chunk color #%d. %v", chunkIDStr, i, err)
-				}
-				err = binary.Read(r, binary.LittleEndian, &vG)
-				if err != nil {
-					return 0, fmt.Errorf("Failed to read the %s chunk color #%d. %v", chunkIDStr, i, err)
-				}
-				err = binary.Read(r, binary.LittleEndian, &vB)
-				if err != nil {
-					return 0, fmt.Errorf("Failed to read the %s chunk color #%d. %v", chunkIDStr, i, err)
-				}
-				err = binary.Read(r, binary.LittleEndian, &vA)
-				if err != nil {
-					return 0, fmt.Errorf("Failed to read the %s chunk color #%d. %v", chunkIDStr, i, err)
-				}
-
-				color := &Color{vR, vG, vB, vA}
-				customPalette[i] = color
-			}
-
-			voxFile.Palette = customPalette
-		} else {
-			// this is a chunk that must have been deprecated in the new file format.
-			// just read it in and ditch the data
-			totalRead := 0
-			contents := make([]byte, 256)
-			_ = contents
-			for totalRead < int(chunkSize) {
-				c, err := r.Read(chunkID[:])
-				if err != nil {
-					return 0, fmt.Errorf("Failed to read the %s chunk contents. %v", chunkIDStr, err)
-				}
-				totalRead += c
-			}
-		}
-	}
-
-	// read children if necessary
-	remainingBytes := chunkChildrenSize
-	for remainingBytes > 0 {
-		childReadSize, err := readChunk(r, voxFile)
-		if err != nil {
-			return 0, err
-		}
-		remainingBytes = remainingBytes - childReadSize
-	}
-
-	return chunkSize + 12, nil // +12 bytes for id, size, childSize
+	return voxelFile, err
 }
 
 func instancePalette(p [256]uint32) []*Color {
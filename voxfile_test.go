@@ -11,6 +11,24 @@ const (
 	voxfileCharacter = "testdata/chr_sword.vox"
 )
 
+// newCustomPaletteVoxFile builds a VoxFile with a single voxel and a
+// genuinely non-default 256-color palette, shared by tests that need to
+// prove a custom palette survives round-tripping rather than being
+// silently replaced by the default one.
+func newCustomPaletteVoxFile() *VoxFile {
+	v := &VoxFile{
+		Version: CurrentVersion,
+		Models: []*Model{
+			{SizeX: 1, SizeY: 1, SizeZ: 1, Voxels: []*Voxel{{X: 0, Y: 0, Z: 0, Index: 10}}},
+		},
+		Palette: make([]*Color, 256),
+	}
+	for i := range v.Palette {
+		v.Palette[i] = &Color{R: uint8(i), G: uint8(255 - i), B: 1, A: 255}
+	}
+	return v
+}
+
 // TestFileLoad tests the loading and decoding of a vox file
 func TestFileLoad(t *testing.T) {
 	voxFile, err := DecodeFile(voxfileCharacter)